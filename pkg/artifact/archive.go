@@ -2,7 +2,7 @@ package artifact
 
 import (
 	"archive/tar"
-	"compress/gzip"
+	"context"
 	"io"
 	"io/fs"
 	"os"
@@ -13,23 +13,54 @@ import (
 	"golang.org/x/exp/maps"
 )
 
-// WriteArchive writes a GZip-compressed TAR to out containing the files and directories given in manifest.
+// Options configures how WriteArchive compresses its output.
+type Options struct {
+	// Compression selects the registered Compression to use by name,
+	// e.g. "gzip", "zstd" or "zstd:chunked". The zero value defaults
+	// to "gzip".
+	Compression string
+}
+
+// WriteArchive writes a compressed TAR to out containing the files and directories given in manifest, using the
+// algorithm selected by opts.Compression.
 // The keys in manifest correspond to the path within the archive, the corresponding value is expected to be the
 // absolute path of the file or directory on disk.
 // Note: WriteArchive *does not* (recursively) traverse directories to add their contents to the archive. If this is
 // desired, use AddDirToManifest to explicitly add the contents to the manifest before calling WriteArchive.
-func WriteArchive(out io.Writer, manifest map[string]string) error {
-	gw := gzip.NewWriter(out)
-	defer gw.Close()
-	tw := tar.NewWriter(gw)
-	defer tw.Close()
+func WriteArchive(out io.Writer, manifest map[string]string, opts Options) error {
+	return WriteArchiveContext(context.Background(), out, manifest, opts)
+}
+
+// WriteArchiveContext is WriteArchive with a context that is checked before each archive entry is written, so that
+// archiving a multi-gigabyte corpus can be cancelled promptly.
+func WriteArchiveContext(ctx context.Context, out io.Writer, manifest map[string]string, opts Options) error {
+	comp, err := compressionByName(opts.Compression)
+	if err != nil {
+		return err
+	}
 
 	// Sort the archive paths first so that the generated archive is deterministic - map traversals aren't.
 	archivePaths := maps.Keys(manifest)
 	sort.Strings(archivePaths)
+
+	if comp.Name() == "zstd:chunked" {
+		return writeChunkedArchive(ctx, out, manifest, archivePaths)
+	}
+
+	cw, err := comp.NewWriter(out)
+	if err != nil {
+		return err
+	}
+	defer cw.Close()
+	tw := tar.NewWriter(cw)
+	defer tw.Close()
+
 	for _, archivePath := range archivePaths {
+		if err := ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
 		absPath := manifest[archivePath]
-		err := addToArchive(tw, archivePath, absPath)
+		err := addToArchive(ctx, tw, archivePath, absPath)
 		if err != nil {
 			return err
 		}
@@ -41,10 +72,19 @@ func WriteArchive(out io.Writer, manifest map[string]string) error {
 // AddDirToManifest traverses the directory dir recursively and adds its contents to the manifest under the base path
 // archiveBasePath.
 func AddDirToManifest(manifest map[string]string, archiveBasePath string, dir string) error {
+	return AddDirToManifestContext(context.Background(), manifest, archiveBasePath, dir)
+}
+
+// AddDirToManifestContext is AddDirToManifest with a context that is checked before each directory entry is
+// visited, so that walking a large directory tree (e.g. over NFS) can be cancelled promptly.
+func AddDirToManifestContext(ctx context.Context, manifest map[string]string, archiveBasePath string, dir string) error {
 	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
 
 		relPath, err := filepath.Rel(dir, path)
 		if err != nil {
@@ -57,62 +97,8 @@ func AddDirToManifest(manifest map[string]string, archiveBasePath string, dir st
 	})
 }
 
-// ExtractArchiveForTestsOnly extracts the GZip-compressed TAR read by in into dir.
-func ExtractArchiveForTestsOnly(in io.Reader, dir string) error {
-	gr, err := gzip.NewReader(in)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-	defer gr.Close()
-	tr := tar.NewReader(gr)
-
-	for {
-		var header *tar.Header
-		header, err = tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		switch header.Typeflag {
-		case tar.TypeDir:
-			err = os.MkdirAll(filepath.Join(dir, header.Name), 0755)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-		case tar.TypeReg:
-			err = func() error {
-				filePath := filepath.Join(dir, header.Name)
-				err = os.MkdirAll(filepath.Dir(filePath), 0755)
-				if err != nil {
-					return errors.WithStack(err)
-				}
-				var file *os.File
-				file, err = os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY, os.FileMode(header.Mode))
-				if err != nil {
-					return errors.WithStack(err)
-				}
-				defer file.Close()
-				_, err = io.Copy(file, tr)
-				if err != nil {
-					return errors.WithStack(err)
-				}
-				return nil
-			}()
-			if err != nil {
-				return err
-			}
-		default:
-			return errors.Errorf("unsupported file type: %d", header.Typeflag)
-		}
-	}
-	return nil
-}
-
 // addToArchive adds the file absPath to the archive under the path archivePath.
-func addToArchive(tw *tar.Writer, archivePath, absPath string) error {
+func addToArchive(ctx context.Context, tw *tar.Writer, archivePath, absPath string) error {
 	fileOrDir, err := os.Open(absPath)
 	if err != nil {
 		return errors.Wrapf(err, "failed to add %q at %q", absPath, archivePath)
@@ -138,7 +124,7 @@ func addToArchive(tw *tar.Writer, archivePath, absPath string) error {
 	if !info.Mode().IsRegular() {
 		return nil
 	}
-	_, err = io.Copy(tw, fileOrDir)
+	_, err = io.Copy(tw, newCtxReader(ctx, fileOrDir))
 	if err != nil {
 		return errors.Wrapf(err, "failed to compress file: %s", absPath)
 	}
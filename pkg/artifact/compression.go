@@ -0,0 +1,138 @@
+package artifact
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// Compression is implemented by the algorithms WriteArchive and
+// ExtractArchive can use to (de)compress an archive's outer stream.
+// Implementations are registered with RegisterCompression and looked
+// up by name or auto-detected from the stream's leading bytes.
+type Compression interface {
+	// Name is the stable identifier used in Options.Compression, e.g.
+	// "gzip".
+	Name() string
+	// NewWriter wraps w so that bytes written to the result are
+	// compressed before reaching w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader wraps r so that bytes read from the result are
+	// decompressed.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+	// Detect reports whether header, a prefix of a stream, was
+	// produced by this algorithm.
+	Detect(header []byte) bool
+}
+
+// compressionHeaderSize is the number of leading bytes that need to be
+// buffered from a stream for every built-in Compression to recognize
+// its magic number.
+const compressionHeaderSize = 4
+
+var compressions = map[string]Compression{}
+
+// RegisterCompression makes a Compression available to WriteArchive
+// and ExtractArchive under the name returned by its Name method. It
+// panics if a compression with the same name is already registered.
+func RegisterCompression(c Compression) {
+	name := c.Name()
+	if _, ok := compressions[name]; ok {
+		panic("artifact: RegisterCompression called twice for " + name)
+	}
+	compressions[name] = c
+}
+
+func init() {
+	RegisterCompression(gzipCompression{})
+	RegisterCompression(zstdCompression{})
+	RegisterCompression(zstdChunkedCompression{})
+}
+
+// compressionByName looks up a Compression registered under name,
+// defaulting to gzip when name is empty to preserve the historical
+// behavior of WriteArchive. ExtractArchive doesn't use it; it
+// auto-detects the compression instead (see detectCompression).
+func compressionByName(name string) (Compression, error) {
+	if name == "" {
+		name = "gzip"
+	}
+	c, ok := compressions[name]
+	if !ok {
+		return nil, errors.Errorf("unknown archive compression %q", name)
+	}
+	return c, nil
+}
+
+// detectCompression peeks at in and returns the registered
+// Compression matching its leading bytes, along with a reader that
+// replays those bytes. It's used by ExtractArchive to accept archives
+// written with any registered Compression without the caller having
+// to name one.
+func detectCompression(in io.Reader) (Compression, io.Reader, error) {
+	header := make([]byte, compressionHeaderSize)
+	n, err := io.ReadFull(in, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, nil, errors.WithStack(err)
+	}
+	header = header[:n]
+	in = io.MultiReader(bytes.NewReader(header), in)
+
+	for _, c := range compressions {
+		if c.Detect(header) {
+			return c, in, nil
+		}
+	}
+	return nil, nil, errors.Errorf("unrecognized archive compression")
+}
+
+type gzipCompression struct{}
+
+func (gzipCompression) Name() string { return "gzip" }
+
+func (gzipCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return gr, nil
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func (gzipCompression) Detect(header []byte) bool {
+	return bytes.HasPrefix(header, gzipMagic)
+}
+
+type zstdCompression struct{}
+
+func (zstdCompression) Name() string { return "zstd" }
+
+func (zstdCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return zw, nil
+}
+
+func (zstdCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return zr.IOReadCloser(), nil
+}
+
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+func (zstdCompression) Detect(header []byte) bool {
+	return bytes.HasPrefix(header, zstdMagic)
+}
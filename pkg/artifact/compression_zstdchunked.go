@@ -0,0 +1,331 @@
+package artifact
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// zstdChunkedTOCMagic is the skippable-frame magic number used for the
+// table of contents appended by zstdChunkedCompression. It falls
+// inside the 0x184D2A50-0x184D2A5F range reserved by the Zstandard
+// format for skippable frames, so archives written with this
+// compression remain readable by any standard zstd decoder, which
+// simply skips the frame.
+const zstdChunkedTOCMagic uint32 = 0x184D2A5F
+
+// zstdChunkedFooterMagic is the skippable-frame magic number used for
+// the fixed-size trailer that points at the TOC frame. It's wrapped in
+// its own skippable frame, rather than appended as raw bytes, so that
+// the entire region following the archive's real tar data stays
+// well-formed Zstandard bitstream - a decoder reading past the last
+// entry never finds anything but frames it knows how to skip.
+const zstdChunkedFooterMagic uint32 = 0x184D2A5E
+
+// zstdChunkedFooterPayloadSize is the size of the footer frame's
+// payload: the TOC frame's absolute offset in the stream.
+const zstdChunkedFooterPayloadSize = 8
+
+// zstdChunkedFooterFrameSize is the total on-disk size of the footer
+// skippable frame (8-byte frame header + its payload), fixed so
+// OpenArchive can find it by seeking from the end of the archive.
+const zstdChunkedFooterFrameSize = 8 + zstdChunkedFooterPayloadSize
+
+// zstdChunkedTOCEntry describes the byte range of a single manifest
+// entry within a "zstd:chunked" archive. Offset and Length are the
+// compressed byte range, not the decompressed one, since each entry
+// is written as its own standalone zstd frame.
+type zstdChunkedTOCEntry struct {
+	ArchivePath string `json:"archive_path"`
+	Offset      int64  `json:"offset"`
+	Length      int64  `json:"length"`
+}
+
+// zstdChunkedCompression implements the skippable-frame-and-TOC
+// layout popularized by containers/storage: every manifest entry is
+// written as its own zstd frame wrapping a one-entry tar, so a
+// consumer holding the TOC can range-request and decompress a single
+// entry without touching the rest of the archive. Only the final
+// entry's tar stream carries the end-of-archive marker (see
+// writeChunkedArchive), so the concatenation of all entries'
+// decompressed output is also a single valid tar stream - a plain
+// sequential zstd+tar reader walks every entry in order and stops
+// cleanly at the last one, never reaching the trailing TOC and footer
+// frames, which it would otherwise just skip as frames it doesn't
+// recognize.
+type zstdChunkedCompression struct{}
+
+func (zstdChunkedCompression) Name() string { return "zstd:chunked" }
+
+func (zstdChunkedCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return (zstdCompression{}).NewWriter(w)
+}
+
+func (zstdChunkedCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return (zstdCompression{}).NewReader(r)
+}
+
+func (zstdChunkedCompression) Detect(header []byte) bool {
+	return (zstdCompression{}).Detect(header)
+}
+
+// countingWriter tracks the number of bytes written through it so
+// writeChunkedArchive can record each entry's frame offsets.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	if err != nil {
+		return n, errors.WithStack(err)
+	}
+	return n, nil
+}
+
+// writeSkippableFrame writes a Zstandard skippable frame containing
+// data, identified by magic.
+func writeSkippableFrame(w io.Writer, magic uint32, data []byte) error {
+	header := make([]byte, 8)
+	binary.LittleEndian.PutUint32(header[0:4], magic)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(data)))
+	_, err := w.Write(header)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// writeChunkedArchive writes manifest to out as a "zstd:chunked"
+// archive: each entry compressed into its own frame, followed by a
+// TOC frame and a fixed-size footer pointing at it.
+func writeChunkedArchive(ctx context.Context, out io.Writer, manifest map[string]string, archivePaths []string) error {
+	cw := &countingWriter{w: out}
+
+	toc := make([]zstdChunkedTOCEntry, 0, len(archivePaths))
+	for i, archivePath := range archivePaths {
+		if err := ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		absPath := manifest[archivePath]
+		start := cw.n
+
+		zw, err := (zstdCompression{}).NewWriter(cw)
+		if err != nil {
+			return err
+		}
+		tw := tar.NewWriter(zw)
+		err = addToArchive(ctx, tw, archivePath, absPath)
+		if err != nil {
+			return err
+		}
+		// Only the last entry gets the two-zero-block end-of-archive marker (written by tw.Close); every earlier
+		// entry just pads its content to the tar block size (tw.Flush), so a sequential reader decompressing the
+		// whole stream as one continuous tar doesn't stop after the first entry.
+		if i == len(archivePaths)-1 {
+			err = tw.Close()
+		} else {
+			err = tw.Flush()
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		err = zw.Close()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		toc = append(toc, zstdChunkedTOCEntry{
+			ArchivePath: archivePath,
+			Offset:      start,
+			Length:      cw.n - start,
+		})
+	}
+
+	tocBytes, err := json.Marshal(toc)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tocOffset := cw.n
+	err = writeSkippableFrame(cw, zstdChunkedTOCMagic, tocBytes)
+	if err != nil {
+		return err
+	}
+
+	footerPayload := make([]byte, zstdChunkedFooterPayloadSize)
+	binary.LittleEndian.PutUint64(footerPayload, uint64(tocOffset))
+	err = writeSkippableFrame(cw, zstdChunkedFooterMagic, footerPayload)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Reader provides random access to the entries of an archive written
+// with the "zstd:chunked" compression, without decompressing the
+// whole stream.
+type Reader struct {
+	ra  io.ReaderAt
+	toc []zstdChunkedTOCEntry
+}
+
+// OpenArchive parses the table of contents appended to the end of an
+// archive written with Options{Compression: "zstd:chunked"} and
+// returns a Reader that can extract individual entries by archive
+// path.
+func OpenArchive(ra io.ReaderAt, size int64) (*Reader, error) {
+	if size < zstdChunkedFooterFrameSize {
+		return nil, errors.Errorf("archive is too small to contain a zstd:chunked footer")
+	}
+
+	footerFrame := make([]byte, zstdChunkedFooterFrameSize)
+	_, err := ra.ReadAt(footerFrame, size-zstdChunkedFooterFrameSize)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	footerMagic := binary.LittleEndian.Uint32(footerFrame[0:4])
+	footerPayloadSize := binary.LittleEndian.Uint32(footerFrame[4:8])
+	if footerMagic != zstdChunkedFooterMagic || footerPayloadSize != zstdChunkedFooterPayloadSize {
+		return nil, errors.Errorf("archive is missing a zstd:chunked footer")
+	}
+	tocOffset := int64(binary.LittleEndian.Uint64(footerFrame[8:16]))
+
+	frameHeader := make([]byte, 8)
+	_, err = ra.ReadAt(frameHeader, tocOffset)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	frameMagic := binary.LittleEndian.Uint32(frameHeader[0:4])
+	if frameMagic != zstdChunkedTOCMagic {
+		return nil, errors.Errorf("corrupt zstd:chunked table of contents")
+	}
+	tocSize := binary.LittleEndian.Uint32(frameHeader[4:8])
+
+	tocBytes := make([]byte, tocSize)
+	_, err = ra.ReadAt(tocBytes, tocOffset+8)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var toc []zstdChunkedTOCEntry
+	err = json.Unmarshal(tocBytes, &toc)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &Reader{ra: ra, toc: toc}, nil
+}
+
+// Entries returns the archive paths present in the table of contents,
+// in the order they were written.
+func (r *Reader) Entries() []string {
+	paths := make([]string, len(r.toc))
+	for i, e := range r.toc {
+		paths[i] = e.ArchivePath
+	}
+	return paths
+}
+
+// ExtractMatching extracts every entry whose archive path matches one of patterns (see AddGlobToManifest for the
+// pattern syntax) into dir, decompressing only the matching entries' frames instead of the whole archive - the
+// payoff of pairing the zstd:chunked TOC with pattern-based extraction.
+func (r *Reader) ExtractMatching(ctx context.Context, dir string, patterns []string) error {
+	for _, archivePath := range r.Entries() {
+		if err := ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		matched, err := matchesPatterns(archivePath, patterns)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+
+		header, rc, err := r.openEntry(archivePath)
+		if err != nil {
+			return err
+		}
+
+		// AddDirToManifest legitimately adds directory entries alongside the files under them, so they need their
+		// own dispatch here, the same as ExtractArchive's extractEntry switch - otherwise writeExtractedFile would
+		// turn a directory entry into a zero-byte regular file, and a later file entry nested under it would then
+		// fail to extract because its parent isn't a directory.
+		if header.Typeflag == tar.TypeDir {
+			rc.Close()
+			target, err := safeJoin(filepath.Clean(dir), archivePath, ExtractOptions{})
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, defaultDirMode); err != nil {
+				return errors.WithStack(err)
+			}
+			continue
+		}
+
+		err = writeExtractedFile(dir, archivePath, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open returns a reader for the single entry stored at archivePath,
+// decompressing only that entry's frame.
+func (r *Reader) Open(archivePath string) (io.ReadCloser, error) {
+	_, rc, err := r.openEntry(archivePath)
+	return rc, err
+}
+
+// openEntry decompresses the frame for archivePath and positions a tar.Reader at its single entry, returning the
+// entry's header alongside the reader so callers can dispatch on its type without having to re-read it.
+func (r *Reader) openEntry(archivePath string) (*tar.Header, io.ReadCloser, error) {
+	for _, e := range r.toc {
+		if e.ArchivePath != archivePath {
+			continue
+		}
+
+		section := io.NewSectionReader(r.ra, e.Offset, e.Length)
+		zr, err := (zstdCompression{}).NewReader(section)
+		if err != nil {
+			return nil, nil, err
+		}
+		tr := tar.NewReader(zr)
+		header, err := tr.Next()
+		if err != nil {
+			zr.Close()
+			return nil, nil, errors.WithStack(err)
+		}
+		return header, &entryReadCloser{Reader: tr, closer: zr}, nil
+	}
+	return nil, nil, errors.Errorf("archive does not contain entry %q", archivePath)
+}
+
+// entryReadCloser adapts a *tar.Reader positioned at a single entry,
+// plus the underlying decompressor that must be closed once the
+// caller is done reading it.
+type entryReadCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (e *entryReadCloser) Close() error {
+	return e.closer.Close()
+}
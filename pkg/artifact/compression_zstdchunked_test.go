@@ -0,0 +1,72 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTempFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// TestWriteChunkedArchive_SequentialExtractSeesEveryEntry guards against the archive only being walkable through
+// OpenArchive's TOC: a plain sequential reader (gzip/zstd's own extraction path) must also see every entry, not just
+// the first.
+func TestWriteChunkedArchive_SequentialExtractSeesEveryEntry(t *testing.T) {
+	srcDir := t.TempDir()
+	aPath := writeTempFile(t, srcDir, "a.txt", "a contents")
+	bPath := writeTempFile(t, srcDir, "b.txt", "b contents")
+	cPath := writeTempFile(t, srcDir, "c.txt", "c contents")
+
+	manifest := map[string]string{"a.txt": aPath, "b.txt": bPath, "c.txt": cPath}
+
+	var buf bytes.Buffer
+	err := WriteArchiveContext(context.Background(), &buf, manifest, Options{Compression: "zstd:chunked"})
+	require.NoError(t, err)
+
+	dstDir := t.TempDir()
+	err = ExtractArchiveForTestsOnly(bytes.NewReader(buf.Bytes()), dstDir)
+	require.NoError(t, err)
+
+	for name, content := range map[string]string{"a.txt": "a contents", "b.txt": "b contents", "c.txt": "c contents"} {
+		got, err := os.ReadFile(filepath.Join(dstDir, name))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(got))
+	}
+}
+
+// TestOpenArchive_RandomAccessRoundTrip guards the TOC-based extraction path that sequential reads aren't meant to
+// take: OpenArchive must still serve every entry independently after the layout change that lets the final entry's
+// frame carry the end-of-archive marker.
+func TestOpenArchive_RandomAccessRoundTrip(t *testing.T) {
+	srcDir := t.TempDir()
+	aPath := writeTempFile(t, srcDir, "a.txt", "a contents")
+	bPath := writeTempFile(t, srcDir, "b.txt", "b contents")
+
+	manifest := map[string]string{"a.txt": aPath, "b.txt": bPath}
+
+	var buf bytes.Buffer
+	err := WriteArchiveContext(context.Background(), &buf, manifest, Options{Compression: "zstd:chunked"})
+	require.NoError(t, err)
+
+	reader, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a.txt", "b.txt"}, reader.Entries())
+
+	rc, err := reader.Open("b.txt")
+	require.NoError(t, err)
+	defer rc.Close()
+	var got bytes.Buffer
+	_, err = got.ReadFrom(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "b contents", got.String())
+}
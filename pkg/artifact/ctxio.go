@@ -0,0 +1,27 @@
+package artifact
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ctxReader wraps r so that every Read first checks ctx, used to make
+// the io.Copy loops in addToArchive and extraction cancellable
+// promptly instead of running to completion.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func newCtxReader(ctx context.Context, r io.Reader) io.Reader {
+	return &ctxReader{ctx: ctx, r: r}
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return c.r.Read(p)
+}
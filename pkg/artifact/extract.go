@@ -0,0 +1,294 @@
+package artifact
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// defaultFileMode and defaultDirMode are applied to extracted files and directories when opts.PreserveMode is
+// false, instead of the mode stored in the archive, which may come from an untrusted source.
+const (
+	defaultFileMode = 0644
+	defaultDirMode  = 0755
+)
+
+// ExtractOptions configures how ExtractArchive guards against malicious archives.
+type ExtractOptions struct {
+	// PreserveMode, if set, applies the file mode stored in each tar header. Otherwise every extracted file and
+	// directory gets a safe default mode, ignoring whatever the archive requests.
+	PreserveMode bool
+	// MaxEntrySize caps the uncompressed size of any single entry. Zero means unbounded.
+	MaxEntrySize int64
+	// MaxTotalSize caps the sum of the uncompressed size of all entries. Zero means unbounded.
+	MaxTotalSize int64
+	// Unsafe disables all of the above containment and size checks. It only exists for
+	// ExtractArchiveForTestsOnly, which extracts archives that cifuzz itself created.
+	Unsafe bool
+}
+
+// ExtractArchive extracts the compressed TAR read by in into dir, auto-detecting which registered Compression it
+// was written with. Unlike the former ExtractArchiveForTestsOnly, it's safe to use on archives from an untrusted
+// source: entries (including symlink and hardlink targets) that would escape dir are rejected, entry and total
+// uncompressed sizes can be capped to defend against tar bombs, and file modes are not honored unless
+// opts.PreserveMode is set.
+func ExtractArchive(ctx context.Context, in io.Reader, dir string, opts ExtractOptions) error {
+	comp, in, err := detectCompression(in)
+	if err != nil {
+		return err
+	}
+	cr, err := comp.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer cr.Close()
+	tr := tar.NewReader(cr)
+
+	cleanDir := filepath.Clean(dir)
+	sizes := &entrySizes{}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		err = extractEntry(ctx, tr, header, cleanDir, opts, sizes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtractMatching is like ExtractArchive, but only entries whose archive path matches one of patterns (doublestar
+// glob syntax, see AddGlobToManifest) are written to dir; the rest are skipped. This lets a caller pull just the
+// findings/**/finding.json entries out of a large remote bundle.
+func ExtractMatching(ctx context.Context, in io.Reader, dir string, patterns []string, opts ExtractOptions) error {
+	comp, in, err := detectCompression(in)
+	if err != nil {
+		return err
+	}
+	cr, err := comp.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer cr.Close()
+	tr := tar.NewReader(cr)
+
+	cleanDir := filepath.Clean(dir)
+	sizes := &entrySizes{}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		matched, err := matchesPatterns(header.Name, patterns)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			// tar.Reader.Next discards whatever of the current entry wasn't read, so we don't need to drain it
+			// ourselves before moving on.
+			continue
+		}
+
+		err = extractEntry(ctx, tr, header, cleanDir, opts, sizes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entrySizes accumulates the uncompressed size seen so far across the entries of one extraction, so
+// ExtractOptions.MaxTotalSize can be enforced across ExtractArchive and ExtractMatching's entry loops alike.
+type entrySizes struct {
+	total int64
+}
+
+// extractEntry applies the containment and size checks configured by opts and then writes header's entry to disk
+// under cleanDir, dispatching on its type.
+func extractEntry(ctx context.Context, tr *tar.Reader, header *tar.Header, cleanDir string, opts ExtractOptions, sizes *entrySizes) error {
+	target, err := safeJoin(cleanDir, header.Name, opts)
+	if err != nil {
+		return err
+	}
+
+	if !opts.Unsafe {
+		if opts.MaxEntrySize > 0 && header.Size > opts.MaxEntrySize {
+			return errors.Errorf("archive entry %q exceeds the maximum entry size of %d bytes", header.Name, opts.MaxEntrySize)
+		}
+		sizes.total += header.Size
+		if opts.MaxTotalSize > 0 && sizes.total > opts.MaxTotalSize {
+			return errors.Errorf("archive exceeds the maximum total size of %d bytes", opts.MaxTotalSize)
+		}
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return errors.WithStack(os.MkdirAll(target, dirMode(header, opts)))
+	case tar.TypeReg:
+		return extractRegular(ctx, tr, target, header, opts)
+	case tar.TypeSymlink:
+		return extractSymlink(target, header, cleanDir, opts)
+	case tar.TypeLink:
+		return extractHardlink(target, header, cleanDir, opts)
+	default:
+		return errors.Errorf("unsupported file type: %d", header.Typeflag)
+	}
+}
+
+// ExtractArchiveForTestsOnly extracts the compressed TAR read by in into dir. It trusts the archive completely and
+// exists only because test fixtures sometimes use symlinks or unusual modes that a real-world untrusted bundle
+// wouldn't be allowed to use; test code that extracts archives downloaded or shared by others should call
+// ExtractArchive directly instead.
+func ExtractArchiveForTestsOnly(in io.Reader, dir string) error {
+	return ExtractArchive(context.Background(), in, dir, ExtractOptions{PreserveMode: true, Unsafe: true})
+}
+
+// safeJoin joins name onto dir the way a tar header name or link target is meant to be interpreted - relative to
+// the extraction directory - and, unless opts.Unsafe is set, rejects the classic Zip-Slip escape where a cleaned
+// join still points outside dir.
+func safeJoin(dir, name string, opts ExtractOptions) (string, error) {
+	target := filepath.Join(dir, name)
+	if opts.Unsafe {
+		return target, nil
+	}
+	sep := string(os.PathSeparator)
+	if target != dir && !strings.HasPrefix(target+sep, dir+sep) {
+		return "", errors.Errorf("archive entry %q escapes the extraction directory", name)
+	}
+	return target, nil
+}
+
+func dirMode(header *tar.Header, opts ExtractOptions) os.FileMode {
+	if opts.PreserveMode {
+		return os.FileMode(header.Mode)
+	}
+	return defaultDirMode
+}
+
+func fileMode(header *tar.Header, opts ExtractOptions) os.FileMode {
+	if opts.PreserveMode {
+		return os.FileMode(header.Mode)
+	}
+	return defaultFileMode
+}
+
+func extractRegular(ctx context.Context, tr *tar.Reader, target string, header *tar.Header, opts ExtractOptions) error {
+	err := os.MkdirAll(filepath.Dir(target), defaultDirMode)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fileMode(header, opts))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer file.Close()
+
+	// Bound the copy to the size recorded in the header instead of trusting the compressed stream to stop there,
+	// so a corrupted or hostile header can't make us write more than was declared.
+	src := io.LimitReader(newCtxReader(ctx, tr), header.Size)
+	_, err = io.Copy(file, src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func extractSymlink(target string, header *tar.Header, cleanDir string, opts ExtractOptions) error {
+	if !opts.Unsafe {
+		// An absolute link target ignores cleanDir entirely at resolution time, so filepath.Join below would just
+		// append it onto cleanDir instead of rejecting it - check for that case explicitly first.
+		if filepath.IsAbs(header.Linkname) {
+			return errors.Errorf("symlink %q has an absolute target %q, which escapes the extraction directory", header.Name, header.Linkname)
+		}
+		resolved := filepath.Join(filepath.Dir(target), header.Linkname)
+		sep := string(os.PathSeparator)
+		if resolved != cleanDir && !strings.HasPrefix(resolved+sep, cleanDir+sep) {
+			return errors.Errorf("symlink %q targets %q, which escapes the extraction directory", header.Name, header.Linkname)
+		}
+	}
+
+	err := os.MkdirAll(filepath.Dir(target), defaultDirMode)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Remove a previous entry at target, since os.Symlink fails if it already exists.
+	err = os.Remove(target)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	err = os.Symlink(header.Linkname, target)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func extractHardlink(target string, header *tar.Header, cleanDir string, opts ExtractOptions) error {
+	linkTarget, err := safeJoin(cleanDir, header.Linkname, opts)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(target), defaultDirMode)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = os.Remove(target)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	err = os.Link(linkTarget, target)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// writeExtractedFile writes the content read from r to archivePath under dir, rejecting the entry if it would
+// escape dir. It's used by Reader.ExtractMatching, which extracts single entries from a "zstd:chunked" archive
+// that are already known-good tar entries read through Reader.openEntry.
+func writeExtractedFile(dir, archivePath string, r io.Reader) error {
+	target, err := safeJoin(filepath.Clean(dir), archivePath, ExtractOptions{})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(target), defaultDirMode)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaultFileMode)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer file.Close()
+	_, err = io.Copy(file, r)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package artifact
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTarGzWithContent is buildTarGz plus the file content each header declares in Size, since the tar format
+// (and thus ExtractOptions.MaxEntrySize/MaxTotalSize, which are checked against header.Size) requires entries to
+// actually carry that many content bytes.
+func buildTarGzWithContent(t *testing.T, entries map[*tar.Header]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for header, content := range entries {
+		require.NoError(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return &buf
+}
+
+func TestExtractArchive_RejectsEntryOverMaxEntrySize(t *testing.T) {
+	content := strings.Repeat("a", 2048)
+	archive := buildTarGzWithContent(t, map[*tar.Header]string{
+		{Name: "big.txt", Typeflag: tar.TypeReg, Size: int64(len(content)), Mode: 0644}: content,
+	})
+
+	dir := t.TempDir()
+	err := ExtractArchive(context.Background(), archive, dir, ExtractOptions{MaxEntrySize: 1024})
+	assert.Error(t, err)
+	assert.ErrorContains(t, err, "exceeds the maximum entry size")
+}
+
+func TestExtractArchive_AllowsEntryUnderMaxEntrySize(t *testing.T) {
+	content := "small"
+	archive := buildTarGzWithContent(t, map[*tar.Header]string{
+		{Name: "small.txt", Typeflag: tar.TypeReg, Size: int64(len(content)), Mode: 0644}: content,
+	})
+
+	dir := t.TempDir()
+	err := ExtractArchive(context.Background(), archive, dir, ExtractOptions{MaxEntrySize: 1024})
+	assert.NoError(t, err)
+}
+
+func TestExtractArchive_RejectsArchiveOverMaxTotalSize(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		content := strings.Repeat("x", 100)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Size: int64(len(content)), Mode: 0644}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	dir := t.TempDir()
+	err := ExtractArchive(context.Background(), &buf, dir, ExtractOptions{MaxTotalSize: 150})
+	assert.Error(t, err)
+	assert.ErrorContains(t, err, "exceeds the maximum total size")
+}
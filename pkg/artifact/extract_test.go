@@ -0,0 +1,84 @@
+package artifact
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTarGz writes headers (with no file content) to a gzip-compressed tar, for exercising ExtractArchive's
+// containment checks without needing real files on disk to add via addToArchive.
+func buildTarGz(t *testing.T, headers []*tar.Header) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, header := range headers {
+		require.NoError(t, tw.WriteHeader(header))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return &buf
+}
+
+func TestExtractArchive_RejectsEscapingEntries(t *testing.T) {
+	cases := []struct {
+		name    string
+		headers []*tar.Header
+	}{
+		{
+			name: "regular file escapes via ..",
+			headers: []*tar.Header{
+				{Name: "../evil.txt", Typeflag: tar.TypeReg, Size: 0, Mode: 0644},
+			},
+		},
+		{
+			name: "symlink targets an absolute path",
+			headers: []*tar.Header{
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd", Mode: 0777},
+			},
+		},
+		{
+			name: "symlink target climbs out via ..",
+			headers: []*tar.Header{
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../../../../etc/passwd", Mode: 0777},
+			},
+		},
+		{
+			name: "hardlink target climbs out via ..",
+			headers: []*tar.Header{
+				{Name: "link", Typeflag: tar.TypeLink, Linkname: "../../../../etc/passwd", Mode: 0644},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := ExtractArchive(context.Background(), buildTarGz(t, c.headers), dir, ExtractOptions{})
+			assert.Error(t, err)
+			assert.ErrorContains(t, err, "escapes the extraction directory")
+		})
+	}
+}
+
+func TestExtractArchive_AllowsContainedSymlink(t *testing.T) {
+	dir := t.TempDir()
+	headers := []*tar.Header{
+		{Name: "target.txt", Typeflag: tar.TypeReg, Size: 0, Mode: 0644},
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "target.txt", Mode: 0777},
+	}
+	err := ExtractArchive(context.Background(), buildTarGz(t, headers), dir, ExtractOptions{})
+	require.NoError(t, err)
+
+	resolved, err := os.Readlink(filepath.Join(dir, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "target.txt", resolved)
+}
@@ -0,0 +1,65 @@
+package artifact
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/pkg/errors"
+)
+
+// AddGlobToManifest walks rootDir once and adds every file matching any of patterns to manifest under
+// archiveBasePath. Patterns are doublestar-style glob patterns (e.g. "**/*.c", "corpus/**"); a pattern prefixed
+// with "!" excludes files it matches that a preceding pattern included. This lets callers express "ship sources
+// and seed corpora but skip build outputs" as a list of patterns instead of pre-filtering the file list
+// themselves.
+func AddGlobToManifest(manifest map[string]string, archiveBasePath, rootDir string, patterns []string) error {
+	return filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		matched, err := matchesPatterns(relPath, patterns)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			return nil
+		}
+
+		archivePath := filepath.Join(archiveBasePath, relPath)
+		manifest[archivePath] = path
+		return nil
+	})
+}
+
+// matchesPatterns reports whether relPath should be included given patterns, which are applied in order - each
+// matching "!"-prefixed pattern excludes a path a preceding pattern included, mirroring .gitignore-style
+// precedence. relPath is matched using slash-separated path elements regardless of the host OS.
+func matchesPatterns(relPath string, patterns []string) (bool, error) {
+	relPath = filepath.ToSlash(relPath)
+
+	included := false
+	for _, pattern := range patterns {
+		exclude := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		matched, err := doublestar.Match(pattern, relPath)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		if !matched {
+			continue
+		}
+		included = !exclude
+	}
+	return included, nil
+}
@@ -0,0 +1,75 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReaderExtractMatching_WalksAllEntries reproduces the original bug report against Reader.ExtractMatching: with
+// a 3-entry zstd:chunked archive, pattern-based extraction must select matches from every entry, not just the first.
+// Reader.ExtractMatching reaches each entry through the TOC, so this doesn't rely on the sequential walkability of
+// writeChunkedArchive's layout, which is exercised separately in compression_zstdchunked_test.go.
+func TestReaderExtractMatching_WalksAllEntries(t *testing.T) {
+	srcDir := t.TempDir()
+	aPath := writeTempFile(t, srcDir, "a.txt", "a contents")
+	bPath := writeTempFile(t, srcDir, "b.log", "b contents")
+	cPath := writeTempFile(t, srcDir, "c.txt", "c contents")
+
+	manifest := map[string]string{"a.txt": aPath, "b.log": bPath, "c.txt": cPath}
+
+	var buf bytes.Buffer
+	err := WriteArchiveContext(context.Background(), &buf, manifest, Options{Compression: "zstd:chunked"})
+	require.NoError(t, err)
+
+	reader, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	dstDir := t.TempDir()
+	err = reader.ExtractMatching(context.Background(), dstDir, []string{"*.txt"})
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dstDir, "a.txt"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dstDir, "c.txt"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dstDir, "b.log"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestReaderExtractMatching_HandlesDirectoryEntries reproduces a crash reported against Reader.ExtractMatching:
+// AddDirToManifest legitimately adds directory entries alongside the files nested under them, but
+// writeExtractedFile always opened its target as a regular file - turning "sub" into a zero-byte file before
+// "sub/a.txt" could be extracted under it, which then failed because "sub" wasn't a directory.
+func TestReaderExtractMatching_HandlesDirectoryEntries(t *testing.T) {
+	srcDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(srcDir, "sub"), 0755))
+	writeTempFile(t, filepath.Join(srcDir, "sub"), "a.txt", "a contents")
+
+	manifest := map[string]string{}
+	require.NoError(t, AddDirToManifest(manifest, "", srcDir))
+
+	var buf bytes.Buffer
+	err := WriteArchiveContext(context.Background(), &buf, manifest, Options{Compression: "zstd:chunked"})
+	require.NoError(t, err)
+
+	reader, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	dstDir := t.TempDir()
+	err = reader.ExtractMatching(context.Background(), dstDir, []string{"**"})
+	require.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(dstDir, "sub"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	got, err := os.ReadFile(filepath.Join(dstDir, "sub", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a contents", string(got))
+}
@@ -1,10 +1,13 @@
 package finding
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,12 +19,12 @@ import (
 	"code-intelligence.com/cifuzz/internal/cmd/run/report_handler/stacktrace"
 	"code-intelligence.com/cifuzz/pkg/log"
 	"code-intelligence.com/cifuzz/util/fileutil"
-	"code-intelligence.com/cifuzz/util/sliceutil"
 )
 
 const nameCrashingInput = "crashing-input"
 const nameJsonFile = "finding.json"
 const nameFindingsDir = ".cifuzz-findings"
+const nameInputIndexFile = "inputs.index.json"
 const lockFile = ".lock"
 
 type Finding struct {
@@ -41,6 +44,11 @@ type Finding struct {
 	CreatedAt  time.Time                `json:"created_at,omitempty"`
 	StackTrace []*stacktrace.StackFrame `json:"stack_trace,omitempty"`
 
+	// Digests are the SHA-256 digests of this finding's crashing inputs (see InputDigests), snapshotted into the
+	// finding's JSON by SaveContext so that LoadFindingContext callers get a stable identifier for input identity
+	// without having to re-read the digest index themselves.
+	Digests []string `json:"input_digests,omitempty"`
+
 	seedPath string
 }
 
@@ -89,6 +97,17 @@ func (f *Finding) Exists(projectDir string) (bool, error) {
 }
 
 func (f *Finding) Save(projectDir string) error {
+	return f.SaveContext(context.Background(), projectDir)
+}
+
+// SaveContext is Save with a context that is checked before the finding directory is created and before the JSON
+// file is written. It also snapshots the finding's current input digests (see InputDigests) into Digests before
+// writing the JSON file.
+func (f *Finding) SaveContext(ctx context.Context, projectDir string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
 	findingDir := filepath.Join(projectDir, nameFindingsDir, f.Name)
 
 	err := os.MkdirAll(findingDir, 0755)
@@ -107,6 +126,19 @@ func (f *Finding) Save(projectDir string) error {
 		return WrapAlreadyExistsError(errors.Errorf("Finding %s already exists", f.Name))
 	}
 
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Snapshot the current digest index into the finding itself, so that LoadFindingContext callers get a stable
+	// identifier for input identity without needing projectDir to re-read the index. Callers that move input files
+	// in via MoveInputFileContext are expected to do so before calling SaveContext.
+	digests, err := f.InputDigests(projectDir)
+	if err != nil {
+		return err
+	}
+	f.Digests = digests
+
 	if err := f.saveJson(jsonPath); err != nil {
 		return err
 	}
@@ -130,6 +162,16 @@ func (f *Finding) saveJson(jsonPath string) error {
 // MoveInputFile copies the input file to the finding directory and
 // the seed corpus directory and adjusts the finding logs accordingly.
 func (f *Finding) MoveInputFile(projectDir, seedCorpusDir string) error {
+	return f.MoveInputFileContext(context.Background(), projectDir, seedCorpusDir)
+}
+
+// MoveInputFileContext is MoveInputFile with a context that is checked before the file lock is acquired and before
+// the input file is copied, so that moving a large input across a mounted filesystem can be cancelled promptly.
+func (f *Finding) MoveInputFileContext(ctx context.Context, projectDir, seedCorpusDir string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
 	// Acquire a file lock to avoid races with other cifuzz processes
 	// running in parallel
 	findingDir := filepath.Join(projectDir, nameFindingsDir, f.Name)
@@ -148,7 +190,7 @@ func (f *Finding) MoveInputFile(projectDir, seedCorpusDir string) error {
 	}
 
 	// Actually move the input file
-	err = f.moveInputFile(projectDir, seedCorpusDir)
+	err = f.moveInputFile(ctx, projectDir, seedCorpusDir)
 
 	// Release the file lock
 	unlockErr := mutex.Unlock()
@@ -161,37 +203,27 @@ func (f *Finding) MoveInputFile(projectDir, seedCorpusDir string) error {
 	return err
 }
 
-func (f *Finding) moveInputFile(projectDir, seedCorpusDir string) error {
+func (f *Finding) moveInputFile(ctx context.Context, projectDir, seedCorpusDir string) error {
 	findingDir := filepath.Join(projectDir, nameFindingsDir, f.Name)
 
-	// Choose the new name of the input file. If the finding already
-	// exists and we just found another input which causes the same
-	// crash, we copy the input file to the existing finding directory
-	// and increase the number at the end of the filename.
-	var path string
-	i := 1
-	for {
-		path = filepath.Join(findingDir, nameCrashingInput+"-"+strconv.Itoa(i))
-		exists, err := fileutil.Exists(path)
-		if err != nil {
-			return err
-		}
-		if !exists {
-			// We found a filename which doesn't exist yet
-			break
-		}
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
 
-		// Check if the existing input file and the new file are
-		// identical
-		contentExistingFile, err := os.ReadFile(path)
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		contentNewFile, err := os.ReadFile(f.InputFile)
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		if sliceutil.Equal(contentExistingFile, contentNewFile) {
+	// Digest the new input once, streaming it through SHA-256 instead
+	// of reading every existing crashing input into memory, and look
+	// it up in the finding's digest index to decide whether we've
+	// already seen this exact input.
+	digest, err := digestFile(f.InputFile)
+	if err != nil {
+		return err
+	}
+	idx, err := loadInputIndex(findingDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range idx.Entries {
+		if entry.Digest == digest {
 			// The input file already exists in the finding
 			// directory, so we don't copy it there again.
 			// We also don't copy it to the seed corpus, because
@@ -200,18 +232,27 @@ func (f *Finding) moveInputFile(projectDir, seedCorpusDir string) error {
 			// again.
 			return nil
 		}
-
-		i += 1
 	}
 
+	// Choose the new name of the input file. If the finding already
+	// exists and we just found another input which causes the same
+	// crash, we copy the input file to the existing finding directory
+	// and increase the number at the end of the filename.
+	i := len(idx.Entries) + 1
+	path := filepath.Join(findingDir, nameCrashingInput+"-"+strconv.Itoa(i))
+
 	// Copy the input file to the finding dir. We don't use os.Rename to
 	// avoid errors when source and target are not on the same mounted
 	// filesystem.
-	err := copy.Copy(f.InputFile, path)
+	err = copy.Copy(f.InputFile, path)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
 	// Copy the input file to the seed corpus dir. We reuse the number
 	// from the filename in the finding dir to make it more obvious that
 	// the input file in the seed corpus is the same as the input
@@ -255,35 +296,197 @@ func (f *Finding) moveInputFile(projectDir, seedCorpusDir string) error {
 		return errors.WithStack(err)
 	}
 	f.InputFile = pathRelativeToProjectDir
+
+	// Record the new input in the digest index so that future calls can
+	// recognize it without reading the file content again.
+	idx.Entries = append(idx.Entries, inputIndexEntry{
+		Digest:    digest,
+		Filename:  filepath.Base(path),
+		CreatedAt: time.Now(),
+	})
+	err = saveInputIndex(findingDir, idx)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// ListFindings parses the JSON files of all findings and returns the
-// result.
-func ListFindings(projectDir string) ([]*Finding, error) {
-	findingsDir := filepath.Join(projectDir, nameFindingsDir)
-	entries, err := os.ReadDir(findingsDir)
+// InputDigests returns the SHA-256 digests (hex-encoded) of all
+// crashing inputs stored for this finding, in the order they were
+// added. It reads the on-disk digest index rather than hashing the
+// input files again, so it's cheap to call repeatedly. Findings saved
+// before the index existed are migrated to one on first access.
+// SaveContext calls this to snapshot the result into the finding's
+// Digests field, so most callers can just read Digests off a loaded
+// Finding instead of calling this directly.
+func (f *Finding) InputDigests(projectDir string) ([]string, error) {
+	findingDir := filepath.Join(projectDir, nameFindingsDir, f.Name)
+	idx, err := loadInputIndex(findingDir)
+	if err != nil {
+		return nil, err
+	}
+	digests := make([]string, len(idx.Entries))
+	for i, entry := range idx.Entries {
+		digests[i] = entry.Digest
+	}
+	return digests, nil
+}
+
+// inputIndexEntry is a single entry of a finding's digest index,
+// mapping the SHA-256 digest of a crashing input to the name it was
+// archived under.
+type inputIndexEntry struct {
+	Digest    string    `json:"digest"`
+	Filename  string    `json:"filename"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// inputIndex is the content of a finding directory's
+// inputs.index.json sidecar file, which lets moveInputFile recognize
+// a duplicate crashing input by digest lookup instead of reading and
+// comparing every previously stored input file.
+type inputIndex struct {
+	Entries []inputIndexEntry `json:"entries"`
+}
+
+// loadInputIndex reads the digest index of the finding directory
+// findingDir, building it by migrating any pre-existing
+// crashing-input-N files if the index doesn't exist yet.
+func loadInputIndex(findingDir string) (*inputIndex, error) {
+	indexPath := filepath.Join(findingDir, nameInputIndexFile)
+	bytes, err := os.ReadFile(indexPath)
 	if os.IsNotExist(err) {
-		return []*Finding{}, nil
+		return migrateInputIndex(findingDir)
 	}
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	var idx inputIndex
+	err = json.Unmarshal(bytes, &idx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &idx, nil
+}
+
+// saveInputIndex writes idx to findingDir. It writes to a temporary
+// file and renames it into place so that a reader never observes a
+// partially written index.
+func saveInputIndex(findingDir string, idx *inputIndex) error {
+	bytes, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmpFile, err := os.CreateTemp(findingDir, nameInputIndexFile+".tmp-*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(bytes)
+	if err != nil {
+		tmpFile.Close()
+		return errors.WithStack(err)
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	indexPath := filepath.Join(findingDir, nameInputIndexFile)
+	err = os.Rename(tmpFile.Name(), indexPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
 
-	var res []*Finding
-	for _, e := range entries {
-		f, err := LoadFinding(projectDir, e.Name())
+// migrateInputIndex builds the digest index for a finding directory
+// that predates it, by hashing its existing crashing-input-N files.
+// It's a one-shot migration: the resulting index is saved immediately
+// so that subsequent loads read it instead of re-hashing the inputs.
+func migrateInputIndex(findingDir string) (*inputIndex, error) {
+	var idx inputIndex
+	for i := 1; ; i++ {
+		path := filepath.Join(findingDir, nameCrashingInput+"-"+strconv.Itoa(i))
+		exists, err := fileutil.Exists(path)
 		if err != nil {
 			return nil, err
 		}
-		res = append(res, f)
+		if !exists {
+			break
+		}
+		digest, err := digestFile(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		idx.Entries = append(idx.Entries, inputIndexEntry{
+			Digest:    digest,
+			Filename:  filepath.Base(path),
+			CreatedAt: info.ModTime(),
+		})
 	}
 
-	// Sort the findings by date, starting with the newest
-	sort.SliceStable(res, func(i, j int) bool {
-		return res[i].CreatedAt.After(res[j].CreatedAt)
-	})
+	err := os.MkdirAll(findingDir, 0755)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = saveInputIndex(findingDir, &idx)
+	if err != nil {
+		return nil, err
+	}
+	return &idx, nil
+}
+
+// digestFile computes the SHA-256 digest of the file at path,
+// streaming its content through the hash instead of reading it into
+// memory in full.
+func digestFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ListFindings parses the JSON files of all findings and returns the
+// result, sorted by date with the newest first.
+func ListFindings(projectDir string) ([]*Finding, error) {
+	return ListFindingsContext(context.Background(), projectDir)
+}
 
+// ListFindingsContext is ListFindings with a context that is checked before each finding is loaded, so that
+// listing a project with many findings (e.g. over NFS) can be cancelled promptly. It's a compatibility wrapper
+// around IterateFindings for callers that want the full, eagerly loaded list rather than an iterator.
+func ListFindingsContext(ctx context.Context, projectDir string) ([]*Finding, error) {
+	it, err := IterateFindings(ctx, projectDir, IterOptions{SortBy: SortByCreatedAt})
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialized rather than left as a nil slice so callers that JSON-marshal the result (e.g. `cifuzz findings
+	// --json`) get `[]` for a project with no findings dir, matching the baseline behavior this wraps.
+	res := []*Finding{}
+	for {
+		f, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			break
+		}
+		res = append(res, f)
+	}
 	return res, nil
 }
 
@@ -291,6 +494,15 @@ func ListFindings(projectDir string) ([]*Finding, error) {
 // the result.
 // If the specified finding does not exist, a NotExistError is returned.
 func LoadFinding(projectDir, findingName string) (*Finding, error) {
+	return LoadFindingContext(context.Background(), projectDir, findingName)
+}
+
+// LoadFindingContext is LoadFinding with a context that is checked before the finding's JSON file is read.
+func LoadFindingContext(ctx context.Context, projectDir, findingName string) (*Finding, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	findingDir := filepath.Join(projectDir, nameFindingsDir, findingName)
 	jsonPath := filepath.Join(findingDir, nameJsonFile)
 	bytes, err := os.ReadFile(jsonPath)
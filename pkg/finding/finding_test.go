@@ -0,0 +1,32 @@
+package finding
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSaveContext_SnapshotsInputDigests guards the stable-identifier wiring requested alongside the digest index:
+// digests recorded by MoveInputFileContext before a finding is first saved must show up both on the in-memory
+// Finding and in the persisted JSON that LoadFindingContext later returns.
+func TestSaveContext_SnapshotsInputDigests(t *testing.T) {
+	projectDir := t.TempDir()
+
+	inputPath := filepath.Join(projectDir, "crash-input")
+	require.NoError(t, os.WriteFile(inputPath, []byte("crashing input"), 0644))
+
+	seedCorpusDir := filepath.Join(projectDir, "seed-corpus")
+	f := &Finding{Name: "my-finding", InputFile: inputPath}
+
+	require.NoError(t, f.MoveInputFile(projectDir, seedCorpusDir))
+	require.NoError(t, f.Save(projectDir))
+
+	require.Len(t, f.Digests, 1)
+
+	loaded, err := LoadFinding(projectDir, "my-finding")
+	require.NoError(t, err)
+	assert.Equal(t, f.Digests, loaded.Digests)
+}
@@ -0,0 +1,239 @@
+package finding
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SortBy selects the field IterateFindings orders its results by.
+type SortBy int
+
+const (
+	// SortByCreatedAt orders findings newest first, matching ListFindings's historical order.
+	SortByCreatedAt SortBy = iota
+	// SortByName orders findings alphabetically by name.
+	SortByName
+)
+
+// IterOptions configures IterateFindings.
+type IterOptions struct {
+	SortBy SortBy
+	// Limit, if greater than zero, bounds the iterator to the first Limit findings in sort order. IterateFindings
+	// uses this to avoid sorting findings it would discard anyway.
+	Limit int
+}
+
+// FindingHeader holds the handful of a finding's fields cheap enough to read for every finding in a project, used
+// to establish sort order without parsing each finding's full JSON (which, for a crash with a large InputData or
+// Logs, can be the bulk of the file).
+type FindingHeader struct {
+	Name             string    `json:"name,omitempty"`
+	Type             ErrorType `json:"type,omitempty"`
+	CreatedAt        time.Time `json:"created_at,omitempty"`
+	ShortDescription string    `json:"short_description,omitempty"`
+}
+
+// Iter lazily loads findings in the order established by IterateFindings, parsing a finding's full JSON only once
+// Next is called for it.
+type Iter struct {
+	ctx        context.Context
+	projectDir string
+	names      []string
+	pos        int
+}
+
+// IterateFindings returns an Iter over the findings of projectDir, ordered by opts.SortBy. Unlike ListFindings, it
+// never parses a finding's full JSON up front - only the lightweight FindingHeader - so listing a project with
+// thousands of findings doesn't unmarshal InputData and Logs into a Finding the caller may never look at. Reading a
+// header still scans, and briefly copies as raw bytes, every field stored ahead of the header fields in the file,
+// which for a saved Finding includes InputData and Logs.
+func IterateFindings(ctx context.Context, projectDir string, opts IterOptions) (*Iter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	findingsDir := filepath.Join(projectDir, nameFindingsDir)
+	entries, err := os.ReadDir(findingsDir)
+	if os.IsNotExist(err) {
+		return &Iter{ctx: ctx, projectDir: projectDir}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	less := sortLess(opts.SortBy)
+
+	var names []string
+	if opts.Limit > 0 {
+		names, err = topFindingNames(ctx, entries, projectDir, less, opts.Limit)
+	} else {
+		names, err = sortedFindingNames(ctx, entries, projectDir, less)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Iter{ctx: ctx, projectDir: projectDir, names: names}, nil
+}
+
+// Next returns the next finding in the iteration order, or nil once the iterator is exhausted.
+func (it *Iter) Next() (*Finding, error) {
+	if it == nil || it.pos >= len(it.names) {
+		return nil, nil
+	}
+	if err := it.ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	name := it.names[it.pos]
+	it.pos++
+	return LoadFindingContext(it.ctx, it.projectDir, name)
+}
+
+func sortLess(by SortBy) func(a, b FindingHeader) bool {
+	if by == SortByName {
+		return func(a, b FindingHeader) bool { return a.Name < b.Name }
+	}
+	return func(a, b FindingHeader) bool { return a.CreatedAt.After(b.CreatedAt) }
+}
+
+// sortedFindingNames reads every finding's header and sorts the full set - used when there's no Limit to bound
+// the work.
+func sortedFindingNames(ctx context.Context, entries []os.DirEntry, projectDir string, less func(a, b FindingHeader) bool) ([]string, error) {
+	headers := make([]FindingHeader, 0, len(entries))
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		header, err := readFindingHeader(projectDir, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+
+	sort.SliceStable(headers, func(i, j int) bool { return less(headers[i], headers[j]) })
+
+	names := make([]string, len(headers))
+	for i, h := range headers {
+		names[i] = h.Name
+	}
+	return names, nil
+}
+
+// topFindingNames reads every finding's header but keeps only the best limit of them in memory at a time, using a
+// bounded max-heap: each new header is pushed and, once the heap grows past limit, its current worst header is
+// popped back off. This avoids materializing and sorting a header slice for every finding when the caller only
+// wants the first few.
+func topFindingNames(ctx context.Context, entries []os.DirEntry, projectDir string, less func(a, b FindingHeader) bool, limit int) ([]string, error) {
+	hp := &headerHeap{less: less}
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		header, err := readFindingHeader(projectDir, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		heap.Push(hp, header)
+		if hp.Len() > limit {
+			heap.Pop(hp)
+		}
+	}
+
+	sort.SliceStable(hp.headers, func(i, j int) bool { return less(hp.headers[i], hp.headers[j]) })
+
+	names := make([]string, len(hp.headers))
+	for i, h := range hp.headers {
+		names[i] = h.Name
+	}
+	return names, nil
+}
+
+// headerHeap is a container/heap max-heap over FindingHeader, where the root is the worst-ranked header according
+// to less - popping it is how topFindingNames evicts the weakest candidate once the heap exceeds its limit.
+type headerHeap struct {
+	headers []FindingHeader
+	less    func(a, b FindingHeader) bool
+}
+
+func (h headerHeap) Len() int { return len(h.headers) }
+
+func (h headerHeap) Less(i, j int) bool {
+	// Inverted so the root (index 0) is the worst header, not the best.
+	return h.less(h.headers[j], h.headers[i])
+}
+
+func (h headerHeap) Swap(i, j int) { h.headers[i], h.headers[j] = h.headers[j], h.headers[i] }
+
+func (h *headerHeap) Push(x any) {
+	h.headers = append(h.headers, x.(FindingHeader))
+}
+
+func (h *headerHeap) Pop() any {
+	old := h.headers
+	n := len(old)
+	item := old[n-1]
+	h.headers = old[:n-1]
+	return item
+}
+
+// readFindingHeader parses just the name, type, created_at and short_description fields of a finding's JSON file
+// via a streaming json.Decoder, stopping as soon as all of them have been seen instead of decoding the rest of
+// the document.
+func readFindingHeader(projectDir, findingName string) (FindingHeader, error) {
+	jsonPath := filepath.Join(projectDir, nameFindingsDir, findingName, nameJsonFile)
+	file, err := os.Open(jsonPath)
+	if err != nil {
+		return FindingHeader{}, errors.WithStack(err)
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	tok, err := dec.Token()
+	if err != nil {
+		return FindingHeader{}, errors.WithStack(err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return FindingHeader{}, errors.Errorf("expected a JSON object in %s", jsonPath)
+	}
+
+	var header FindingHeader
+	remaining := map[string]bool{"name": true, "type": true, "created_at": true, "short_description": true}
+	for dec.More() && len(remaining) > 0 {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return FindingHeader{}, errors.WithStack(err)
+		}
+		key, _ := keyTok.(string)
+
+		switch key {
+		case "name":
+			err = dec.Decode(&header.Name)
+		case "type":
+			err = dec.Decode(&header.Type)
+		case "created_at":
+			err = dec.Decode(&header.CreatedAt)
+		case "short_description":
+			err = dec.Decode(&header.ShortDescription)
+		default:
+			// Read and discard the value of a field we don't need, without decoding it into anything but its raw
+			// bytes.
+			var discarded json.RawMessage
+			err = dec.Decode(&discarded)
+		}
+		if err != nil {
+			return FindingHeader{}, errors.WithStack(err)
+		}
+		delete(remaining, key)
+	}
+
+	return header, nil
+}
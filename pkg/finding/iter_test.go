@@ -0,0 +1,113 @@
+package finding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func saveFinding(t *testing.T, projectDir, name string, createdAt time.Time) {
+	t.Helper()
+	f := &Finding{Name: name, CreatedAt: createdAt}
+	require.NoError(t, f.Save(projectDir))
+}
+
+func TestIterateFindings_SortByCreatedAt(t *testing.T) {
+	projectDir := t.TempDir()
+	now := time.Now()
+	saveFinding(t, projectDir, "oldest", now.Add(-2*time.Hour))
+	saveFinding(t, projectDir, "newest", now)
+	saveFinding(t, projectDir, "middle", now.Add(-1*time.Hour))
+
+	it, err := IterateFindings(context.Background(), projectDir, IterOptions{SortBy: SortByCreatedAt})
+	require.NoError(t, err)
+
+	var names []string
+	for {
+		f, err := it.Next()
+		require.NoError(t, err)
+		if f == nil {
+			break
+		}
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"newest", "middle", "oldest"}, names)
+}
+
+func TestIterateFindings_SortByName(t *testing.T) {
+	projectDir := t.TempDir()
+	now := time.Now()
+	saveFinding(t, projectDir, "charlie", now)
+	saveFinding(t, projectDir, "alpha", now)
+	saveFinding(t, projectDir, "bravo", now)
+
+	it, err := IterateFindings(context.Background(), projectDir, IterOptions{SortBy: SortByName})
+	require.NoError(t, err)
+
+	var names []string
+	for {
+		f, err := it.Next()
+		require.NoError(t, err)
+		if f == nil {
+			break
+		}
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"alpha", "bravo", "charlie"}, names)
+}
+
+func TestIterateFindings_Limit(t *testing.T) {
+	projectDir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		saveFinding(t, projectDir, name, now.Add(-time.Duration(i)*time.Hour))
+	}
+
+	it, err := IterateFindings(context.Background(), projectDir, IterOptions{SortBy: SortByCreatedAt, Limit: 2})
+	require.NoError(t, err)
+
+	var names []string
+	for {
+		f, err := it.Next()
+		require.NoError(t, err)
+		if f == nil {
+			break
+		}
+		names = append(names, f.Name)
+	}
+	// The two most recently created findings, newest first.
+	assert.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestListFindings_NoFindingsDir(t *testing.T) {
+	projectDir := t.TempDir()
+
+	findings, err := ListFindings(projectDir)
+	require.NoError(t, err)
+	assert.NotNil(t, findings)
+	assert.Empty(t, findings)
+}
+
+func TestIterateFindings_ContextCancellation(t *testing.T) {
+	projectDir := t.TempDir()
+	now := time.Now()
+	saveFinding(t, projectDir, "a", now)
+	saveFinding(t, projectDir, "b", now.Add(-time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it, err := IterateFindings(ctx, projectDir, IterOptions{SortBy: SortByCreatedAt})
+	require.NoError(t, err)
+
+	f, err := it.Next()
+	require.NoError(t, err)
+	require.NotNil(t, f)
+
+	cancel()
+
+	_, err = it.Next()
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}